@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/lib/harmony/harmonydb"
+)
+
+// TerminateCmd enqueues a sector for termination; the TerminateTask batches
+// it into a miner.TerminateSectors message the next time its deadline
+// allows one.
+var TerminateCmd = &cli.Command{
+	Name:      "terminate",
+	Usage:     "Mark a sector for termination",
+	ArgsUsage: "<sp actor id> <sector number>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() != 2 {
+			return xerrors.Errorf("expected 2 arguments, got %d", cctx.NArg())
+		}
+
+		spID, err := strconv.ParseInt(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("parsing sp actor id: %w", err)
+		}
+
+		sectorNumber, err := strconv.ParseInt(cctx.Args().Get(1), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("parsing sector number: %w", err)
+		}
+
+		db, err := harmonydb.NewFromConfig(cctx.Context)
+		if err != nil {
+			return xerrors.Errorf("connecting to harmony db: %w", err)
+		}
+
+		n, err := db.Exec(cctx.Context, `INSERT INTO sectors_terminate (sp_id, sector_number)
+			VALUES ($1, $2) ON CONFLICT (sp_id, sector_number) DO NOTHING`, spID, sectorNumber)
+		if err != nil {
+			return xerrors.Errorf("enqueuing termination: %w", err)
+		}
+		if n == 0 {
+			fmt.Printf("sector %d/%d was already queued for termination\n", spID, sectorNumber)
+			return nil
+		}
+
+		fmt.Printf("sector %d/%d queued for termination\n", spID, sectorNumber)
+		return nil
+	},
+}
+
+// TerminatePendingCmd lists sectors that are queued for termination but not
+// yet confirmed terminated, including those whose TerminateSectors message
+// has been sent but hasn't landed yet.
+var TerminatePendingCmd = &cli.Command{
+	Name:  "terminate-pending",
+	Usage: "List sectors queued for termination",
+	Action: func(cctx *cli.Context) error {
+		db, err := harmonydb.NewFromConfig(cctx.Context)
+		if err != nil {
+			return xerrors.Errorf("connecting to harmony db: %w", err)
+		}
+
+		var rows []struct {
+			SpID           int64   `db:"sp_id"`
+			SectorNumber   int64   `db:"sector_number"`
+			RequestEpoch   *int64  `db:"request_epoch"`
+			TerminateCid   *string `db:"terminate_cid"`
+			AfterTerminate bool    `db:"after_terminate"`
+		}
+
+		err = db.Select(cctx.Context, &rows, `SELECT sp_id, sector_number, request_epoch, terminate_cid, after_terminate
+			FROM sectors_terminate WHERE after_terminate = false ORDER BY sp_id, sector_number`)
+		if err != nil {
+			return xerrors.Errorf("listing pending terminations: %w", err)
+		}
+
+		for _, r := range rows {
+			state := "queued"
+			if r.TerminateCid != nil {
+				state = fmt.Sprintf("message sent: %s", *r.TerminateCid)
+			}
+			fmt.Printf("%d\t%d\t%s\n", r.SpID, r.SectorNumber, state)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,157 @@
+// Command lotus-bench-sdr measures SDR throughput with the benchmarking
+// thread's CPU affinity restricted to sets of growing size (CPUs 0..n-1 for
+// n = 1..max-threads), so operators can compare placements before picking a
+// [Curio.SDR] config for a production curio cluster. It does not choose
+// CPUs by NUMA node.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-commp-utils/zerocomm"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/lotus/provider/lpffi"
+	"github.com/filecoin-project/lotus/provider/lpseal"
+	"github.com/filecoin-project/lotus/storage/sealer/storiface"
+)
+
+var proofTypes = map[string]abi.RegisteredSealProof{
+	"2KiB":      abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+	"8MiB":      abi.RegisteredSealProof_StackedDrg8MiBV1_1,
+	"512MiB":    abi.RegisteredSealProof_StackedDrg512MiBV1_1,
+	"32GiBV1_1": abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+	"64GiBV1_1": abi.RegisteredSealProof_StackedDrg64GiBV1_1,
+}
+
+func main() {
+	app := &cli.App{
+		Name:  "lotus-bench-sdr",
+		Usage: "Benchmark SDR throughput across thread/affinity configurations",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "max-threads", Value: 8, Usage: "largest thread count to benchmark"},
+			&cli.IntFlag{Name: "runs", Value: 3, Usage: "number of SDR runs averaged per configuration"},
+			&cli.StringFlag{Name: "proof-type", Value: "2KiB", Usage: "registered seal proof type to benchmark"},
+		},
+		Action: run,
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(cctx *cli.Context) error {
+	maxThreads := cctx.Int("max-threads")
+	runs := cctx.Int("runs")
+
+	proofType, ok := proofTypes[cctx.String("proof-type")]
+	if !ok {
+		return xerrors.Errorf("unknown proof type %q", cctx.String("proof-type"))
+	}
+
+	for threads := 1; threads <= maxThreads; threads++ {
+		cpus := cpuSetForThreads(threads)
+
+		var total time.Duration
+		for i := 0; i < runs; i++ {
+			d, err := benchOne(cctx.Context, threads, cpus, proofType)
+			if err != nil {
+				return xerrors.Errorf("benchmarking threads=%d: %w", threads, err)
+			}
+			total += d
+		}
+
+		avg := total / time.Duration(runs)
+		fmt.Printf("threads=%-3d cpus=%-20v avg=%s\n", threads, cpus, avg)
+	}
+
+	return nil
+}
+
+// cpuSetForThreads picks logical CPUs 0..threads-1; the NUMA topology is not
+// consulted, so the set can span nodes. lpseal.SDRConfig.NUMANodeCPUs takes
+// the resulting slice directly.
+func cpuSetForThreads(threads int) []int {
+	cpus := make([]int, threads)
+	for i := range cpus {
+		cpus[i] = i
+	}
+	return cpus
+}
+
+// benchOne drives one real SDR replication through lpffi.SealCalls, timing
+// it end to end. It uses a scratch tempSectorManager instead of a live
+// curio sector store, so it measures SDR tree building itself without
+// needing a running node.
+func benchOne(ctx context.Context, threads int, cpus []int, proofType abi.RegisteredSealProof) (time.Duration, error) {
+	unpin, err := lpseal.PinToCPUSet(cpus)
+	if err != nil {
+		return 0, xerrors.Errorf("pinning bench thread: %w", err)
+	}
+	defer unpin()
+
+	baseDir, err := os.MkdirTemp("", "lotus-bench-sdr-")
+	if err != nil {
+		return 0, xerrors.Errorf("creating scratch dir: %w", err)
+	}
+	defer os.RemoveAll(baseDir) //nolint:errcheck
+
+	sc := lpffi.NewSealCalls(&tempSectorManager{baseDir: baseDir}, lpffi.LocalProver{})
+
+	sref := storiface.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: proofType,
+	}
+
+	ssize, err := proofType.SectorSize()
+	if err != nil {
+		return 0, xerrors.Errorf("getting sector size: %w", err)
+	}
+
+	commd := zerocomm.ZeroPieceCommitment(abi.PaddedPieceSize(ssize).Unpadded())
+	ticket := abi.SealRandomness(make([]byte, 32))
+
+	start := time.Now()
+	if err := sc.GenerateSDR(ctx, nil, sref, ticket, commd); err != nil {
+		return 0, xerrors.Errorf("generating sdr: %w", err)
+	}
+
+	return time.Since(start), nil
+}
+
+// tempSectorManager is the minimal lpffi.SectorManager needed to drive
+// GenerateSDR against scratch disk instead of a live curio sector store.
+type tempSectorManager struct {
+	baseDir string
+}
+
+func (m *tempSectorManager) sectorDir(sector storiface.SectorRef) string {
+	return filepath.Join(m.baseDir, fmt.Sprintf("s-t0%d-%d", sector.ID.Miner, sector.ID.Number))
+}
+
+func (m *tempSectorManager) AcquireSector(ctx context.Context, sector storiface.SectorRef, existing, allocate storiface.SectorFileType, pathType storiface.PathType) (storiface.SectorPaths, func(), error) {
+	dir := m.sectorDir(sector)
+	cache := filepath.Join(dir, "cache")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		return storiface.SectorPaths{}, nil, xerrors.Errorf("making cache dir: %w", err)
+	}
+
+	paths := storiface.SectorPaths{
+		Cache:  cache,
+		Sealed: filepath.Join(dir, "sealed"),
+	}
+	return paths, func() {}, nil
+}
+
+func (m *tempSectorManager) Remove(ctx context.Context, sector storiface.SectorRef) error {
+	return os.RemoveAll(m.sectorDir(sector))
+}
@@ -0,0 +1,83 @@
+// Command lotus-prover-server exposes lpffi.LocalProver over JSON-RPC so a
+// curio deployment can run its GPU-heavy SealCommit2/ProveReplicaUpdate2/PoSt
+// work on dedicated hardware instead of on the sealing orchestrator. Build a
+// curio node's lpffi.SealCalls with an lpffi.ProverClient dialled to this
+// server's /rpc/v0 address to route its snark work here.
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/gorilla/mux"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-jsonrpc"
+
+	"github.com/filecoin-project/lotus/provider/lpffi"
+)
+
+var log = logging.Logger("lotus-prover-server")
+
+func main() {
+	logging.SetLogLevel("lotus-prover-server", "INFO") //nolint:errcheck
+
+	app := &cli.App{
+		Name:  "lotus-prover-server",
+		Usage: "Stand-alone GPU prover for curio, reachable over JSON-RPC",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "listen",
+				Usage: "host:port to listen on",
+				Value: "0.0.0.0:4701",
+			},
+		},
+		Action: run,
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
+
+func run(cctx *cli.Context) error {
+	ctx, cancel := context.WithCancel(cctx.Context)
+	defer cancel()
+
+	rpcServer := jsonrpc.NewServer()
+	rpcServer.Register("Prover", lpffi.Prover(lpffi.LocalProver{}))
+
+	mux := mux.NewRouter()
+	mux.Handle("/rpc/v0", rpcServer)
+
+	listenAddr := cctx.String("listen")
+	nl, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return xerrors.Errorf("listen on %s: %w", listenAddr, err)
+	}
+
+	srv := &http.Server{Handler: mux}
+
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt)
+		<-sigCh
+		cancel()
+		_ = srv.Close()
+	}()
+
+	log.Infow("lotus-prover-server listening", "addr", listenAddr)
+
+	err = srv.Serve(nl)
+	if err != nil && err != http.ErrServerClosed {
+		return xerrors.Errorf("serving prover-server: %w", err)
+	}
+
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,75 @@
+package ffi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	ffi "github.com/filecoin-project/filecoin-ffi"
+
+	"github.com/filecoin-project/lotus/lib/harmony/harmonytask"
+	"github.com/filecoin-project/lotus/storage/sealer/storiface"
+)
+
+// SealCalls is the pre-Prover-refactor seam onto the sealing/proving FFI
+// calls. It predates the provider/lpffi.SealCalls + Prover split and is
+// still used by tasks, like snap.ProveTask, that haven't been migrated onto
+// the pluggable-prover path.
+type SealCalls struct {
+	gpuMu   sync.Mutex
+	gpuBusy bool
+}
+
+func NewSealCalls() *SealCalls {
+	return &SealCalls{}
+}
+
+// ReserveGPU reserves the machine's single local GPU for taskID's use for
+// the duration of its snark call, returning false (without error) if it's
+// already claimed by another task so the caller can back off and retry
+// rather than block.
+func (sc *SealCalls) ReserveGPU(taskID harmonytask.TaskID) (bool, error) {
+	sc.gpuMu.Lock()
+	defer sc.gpuMu.Unlock()
+
+	if sc.gpuBusy {
+		return false, nil
+	}
+	sc.gpuBusy = true
+	return true, nil
+}
+
+// ReleaseGPU releases a reservation taken by ReserveGPU. Callers must call
+// this once their snark call returns, whether it succeeded or not.
+func (sc *SealCalls) ReleaseGPU() {
+	sc.gpuMu.Lock()
+	defer sc.gpuMu.Unlock()
+	sc.gpuBusy = false
+}
+
+// ProveReplicaUpdate2 proves a snap-deals replica update. sectorKey is the
+// CommR of the sector's original (pre-update) sealed replica; it must match
+// oldSealed, which is what's actually fed to the vanilla proof generator -
+// passing anything else (e.g. a CommD) silently produces a proof against
+// the wrong data.
+func (sc *SealCalls) ProveReplicaUpdate2(ctx context.Context, sector storiface.SectorRef, sectorKey, oldSealed, newSealed, newUnsealed cid.Cid, updateProofType abi.RegisteredUpdateProof) (storiface.Proof, error) {
+	if !sectorKey.Equals(oldSealed) {
+		return nil, xerrors.Errorf("sector key commitment %s does not match old sealed CommR %s", sectorKey, oldSealed)
+	}
+
+	vanilla, err := ffi.SectorUpdate.GenerateUpdateVanillaProofs(updateProofType, oldSealed, newSealed, newUnsealed)
+	if err != nil {
+		return nil, xerrors.Errorf("generating replica update vanilla proofs: %w", err)
+	}
+
+	out, err := ffi.SectorUpdate.GenerateUpdateProofWithVanilla(updateProofType, oldSealed, newSealed, newUnsealed, vanilla)
+	if err != nil {
+		return nil, xerrors.Errorf("generating replica update proof: %w", err)
+	}
+
+	return out, nil
+}
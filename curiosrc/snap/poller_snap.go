@@ -0,0 +1,71 @@
+package snap
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/lib/harmony/harmonydb"
+	"github.com/filecoin-project/lotus/lib/harmony/harmonytask"
+	"github.com/filecoin-project/lotus/lib/promise"
+)
+
+// SnapPoller mirrors lpseal.SealPoller: it holds the AddTaskFunc promises
+// for each stage of the snap-deals (replica update) pipeline so that tasks
+// can be chained together the same way the regular sealing pipeline is.
+type SnapPoller struct {
+	db *harmonydb.DB
+
+	pollers [numSnapPollers]promise.Promise[harmonytask.AddTaskFunc]
+}
+
+type snapPoller int
+
+const (
+	snapPollerProve snapPoller = iota
+	numSnapPollers
+)
+
+func NewSnapPoller(db *harmonydb.DB) *SnapPoller {
+	return &SnapPoller{db: db}
+}
+
+// Poll runs the snap pipeline's housekeeping passes. It's meant to be
+// called on a timer by whatever wires up the node's other pollers.
+func (sp *SnapPoller) Poll(ctx context.Context) error {
+	return sp.pollProveFailures(ctx, proveMaxFailures)
+}
+
+// pollProveFailures finds sectors whose UpdateProve task has been retried
+// by harmony_task maxFailures times without succeeding and sends them back
+// to the EncodeUpdate stage (clearing task_id_prove and after_encode) so
+// the update data is regenerated and proving is retried clean, instead of
+// harmony retrying the same (likely stale) update data forever.
+func (sp *SnapPoller) pollProveFailures(ctx context.Context, maxFailures int) error {
+	var rows []struct {
+		SpID         int64 `db:"sp_id"`
+		SectorNumber int64 `db:"sector_number"`
+	}
+
+	err := sp.db.Select(ctx, &rows, `
+		SELECT p.sp_id, p.sector_number
+		FROM sectors_snap_pipeline p
+		JOIN harmony_task t ON t.id = p.task_id_prove
+		WHERE p.task_id_prove IS NOT NULL
+		  AND p.after_prove = false
+		  AND t.retries >= $1`, maxFailures)
+	if err != nil {
+		return xerrors.Errorf("finding stuck prove tasks: %w", err)
+	}
+
+	for _, r := range rows {
+		_, err := sp.db.Exec(ctx, `UPDATE sectors_snap_pipeline
+			SET task_id_prove = NULL, after_encode = false
+			WHERE sp_id = $1 AND sector_number = $2`, r.SpID, r.SectorNumber)
+		if err != nil {
+			return xerrors.Errorf("returning sector %d/%d to EncodeUpdate: %w", r.SpID, r.SectorNumber, err)
+		}
+	}
+
+	return nil
+}
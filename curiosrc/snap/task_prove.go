@@ -1,11 +1,19 @@
 package snap
 
 import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
 	"github.com/filecoin-project/lotus/curiosrc/ffi"
 	"github.com/filecoin-project/lotus/curiosrc/seal"
 	"github.com/filecoin-project/lotus/lib/harmony/harmonydb"
 	"github.com/filecoin-project/lotus/lib/harmony/harmonytask"
 	"github.com/filecoin-project/lotus/lib/harmony/resources"
+	"github.com/filecoin-project/lotus/storage/sealer/storiface"
 )
 
 type ProveTask struct {
@@ -13,18 +21,133 @@ type ProveTask struct {
 
 	sc *ffi.SealCalls
 	db *harmonydb.DB
+	sp *SnapPoller
+}
+
+func NewProveTask(sp *SnapPoller, db *harmonydb.DB, sc *ffi.SealCalls, maxProve int) *ProveTask {
+	return &ProveTask{
+		max: maxProve,
+
+		sc: sc,
+		db: db,
+		sp: sp,
+	}
 }
 
 func (p *ProveTask) Do(taskID harmonytask.TaskID, stillOwned func() bool) (done bool, err error) {
-	//TODO implement me
-	panic("implement me")
+	ctx := context.Background()
+
+	// Reserve the GPU here, at the start of Do, rather than in CanAccept:
+	// CanAccept only picks a candidate task, it doesn't guarantee this
+	// machine wins the harmonytask claim race for it. Reserving in
+	// CanAccept and releasing only via a defer in Do left gpuBusy stuck
+	// forever whenever some other machine won the claim instead and this
+	// Do never ran.
+	ok, err := p.sc.ReserveGPU(taskID)
+	if err != nil {
+		return false, xerrors.Errorf("reserving gpu: %w", err)
+	}
+	if !ok {
+		// Another ProveTask already running on this machine holds the GPU.
+		// FAIL-RESP: rely on harmony retry.
+		return false, xerrors.Errorf("gpu busy with another prove task")
+	}
+	defer p.sc.ReleaseGPU()
+
+	var sectorParamsArr []struct {
+		SpID         int64                     `db:"sp_id"`
+		SectorNumber int64                     `db:"sector_number"`
+		UpdateProof  abi.RegisteredUpdateProof `db:"upgrade_proof"`
+
+		OrigSealedCID   string `db:"orig_sealed_cid"`
+		OrigUnsealedCID string `db:"orig_unsealed_cid"`
+		UpdateSealedCID string `db:"update_sealed_cid"`
+		UpdateUnsealed  string `db:"update_unsealed_cid"`
+	}
+
+	err = p.db.Select(ctx, &sectorParamsArr, `
+		SELECT sp_id, sector_number, upgrade_proof,
+		       orig_sealed_cid, orig_unsealed_cid,
+		       update_sealed_cid, update_unsealed_cid
+		FROM sectors_snap_pipeline
+		WHERE task_id_prove = $1`, taskID)
+	if err != nil {
+		return false, xerrors.Errorf("getting sector params: %w", err)
+	}
+
+	if len(sectorParamsArr) != 1 {
+		return false, xerrors.Errorf("expected 1 sector params, got %d", len(sectorParamsArr))
+	}
+	sectorParams := sectorParamsArr[0]
+
+	origSealed, err := cid.Parse(sectorParams.OrigSealedCID)
+	if err != nil {
+		return false, xerrors.Errorf("parsing orig sealed cid: %w", err)
+	}
+	updateSealed, err := cid.Parse(sectorParams.UpdateSealedCID)
+	if err != nil {
+		return false, xerrors.Errorf("parsing update sealed cid: %w", err)
+	}
+	updateUnsealed, err := cid.Parse(sectorParams.UpdateUnsealed)
+	if err != nil {
+		return false, xerrors.Errorf("parsing update unsealed cid: %w", err)
+	}
+
+	sref := storiface.SectorRef{
+		ID: abi.SectorID{
+			Miner:  abi.ActorID(sectorParams.SpID),
+			Number: abi.SectorNumber(sectorParams.SectorNumber),
+		},
+		ProofType: sectorParams.UpdateProof.RegisteredSealProof(),
+	}
+
+	// FAIL: GPU may be unavailable, prover may crash mid-proof
+	// FAIL-RESP: rely on harmony retry; once harmony_task.retries reaches
+	//            MaxFailures, SnapPoller.pollProveFailures returns the
+	//            sector to the EncodeUpdate stage so the update data can be
+	//            regenerated and proving retried clean.
+	//
+	// LATEFAIL: proof is produced but computed against stale data
+	// LATEFAIL-RESP: Submit task validates the proof on-chain before
+	//                committing; a bad proof surfaces as a message failure,
+	//                not a silent corruption.
+
+	// sectorKey is the CommR of the sector's original (pre-update) sealed
+	// replica - the same value as origSealed, not origUnsealed (which is a
+	// CommD and would silently prove against the wrong data).
+	proof, err := p.sc.ProveReplicaUpdate2(ctx, sref, origSealed, origSealed, updateSealed, updateUnsealed, sectorParams.UpdateProof)
+	if err != nil {
+		return false, xerrors.Errorf("proving replica update: %w", err)
+	}
+
+	n, err := p.db.Exec(ctx, `UPDATE sectors_snap_pipeline
+		SET after_prove = true, proof = $3
+		WHERE sp_id = $1 AND sector_number = $2`,
+		sectorParams.SpID, sectorParams.SectorNumber, proof)
+	if err != nil {
+		return false, xerrors.Errorf("store prove success: updating pipeline: %w", err)
+	}
+	if n != 1 {
+		return false, xerrors.Errorf("store prove success: updated %d rows", n)
+	}
+
+	return true, nil
 }
 
+// CanAccept doesn't reserve the GPU itself - accepting a candidate here is
+// no guarantee this machine will actually win the harmonytask claim race
+// for it, and a reservation taken here could outlive a Do that never runs.
+// The GPU is reserved (and released) entirely within Do instead.
 func (p *ProveTask) CanAccept(ids []harmonytask.TaskID, engine *harmonytask.TaskEngine) (*harmonytask.TaskID, error) {
 	id := ids[0]
 	return &id, nil
 }
 
+// proveMaxFailures must match TypeDetails().MaxFailures below; it's the
+// harmony_task.retries threshold SnapPoller.pollProveFailures uses to
+// decide a sector's UpdateProve attempt is stuck rather than just unlucky.
+const proveMaxFailures = 3
+
 func (p *ProveTask) TypeDetails() harmonytask.TaskTypeDetails {
 	gpu := 1.0
 	if seal.IsDevnet {
@@ -38,13 +161,13 @@ func (p *ProveTask) TypeDetails() harmonytask.TaskTypeDetails {
 			Gpu: gpu,
 			Ram: 50 << 30, // todo correct value
 		},
-		MaxFailures: 3,
+		MaxFailures: proveMaxFailures,
 		IAmBored:    nil,
 	}
 }
 
 func (p *ProveTask) Adder(taskFunc harmonytask.AddTaskFunc) {
-	return
+	p.sp.pollers[snapPollerProve].Set(taskFunc)
 }
 
 var _ harmonytask.TaskInterface = &ProveTask{}
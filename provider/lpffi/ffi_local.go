@@ -0,0 +1,121 @@
+package lpffi
+
+import (
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/proof"
+
+	ffi "github.com/filecoin-project/filecoin-ffi"
+
+	"github.com/filecoin-project/lotus/storage/sealer/storiface"
+)
+
+// The functions below are thin wrappers around the raw filecoin-ffi calls.
+// They exist so that LocalProver has something to call that isn't the FFI
+// package directly, keeping the seam between "we are doing local proving"
+// and "we are calling into the FFI" in one place.
+
+// ffiGenerateSDR runs SDR replication (tree building) for sector, writing
+// the result into the cache/sealed paths acquired by the caller. It is
+// CPU-bound, not a snark, so unlike the functions below it is called
+// directly by SealCalls.GenerateSDR rather than through the Prover seam.
+func ffiGenerateSDR(sector storiface.SectorRef, paths storiface.SectorPaths, ticket abi.SealRandomness) error {
+	_, err := ffi.SealPreCommitPhase1(sector.ProofType, paths.Cache, paths.Unsealed, paths.Sealed, sector.ID.Number, sector.ID.Miner, ticket, nil)
+	if err != nil {
+		return xerrors.Errorf("ffi SealPreCommitPhase1: %w", err)
+	}
+	return nil
+}
+
+func ffiSealCommit2(sector storiface.SectorRef, phase1Out storiface.Commit1Out) (storiface.Proof, error) {
+	out, err := ffi.SealCommitPhase2(phase1Out, sector.ID.Number, sector.ID.Miner)
+	if err != nil {
+		return nil, xerrors.Errorf("ffi SealCommitPhase2: %w", err)
+	}
+	return out, nil
+}
+
+func ffiProveReplicaUpdate2(sector storiface.SectorRef, sectorKey, oldSealed, newSealed, newUnsealed cid.Cid, updateProofType abi.RegisteredUpdateProof) (storiface.Proof, error) {
+	vanilla, err := ffi.SectorUpdate.GenerateUpdateVanillaProofs(updateProofType, oldSealed, newSealed, newUnsealed)
+	if err != nil {
+		return nil, xerrors.Errorf("generating replica update vanilla proofs: %w", err)
+	}
+
+	out, err := ffi.SectorUpdate.GenerateUpdateProofWithVanilla(updateProofType, oldSealed, newSealed, newUnsealed, vanilla)
+	if err != nil {
+		return nil, xerrors.Errorf("generating replica update proof: %w", err)
+	}
+
+	return out, nil
+}
+
+func ffiGenerateWindowPoSt(ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) (proof.PoStProof, []abi.SectorNumber, error) {
+	privSectors, err := toFFIPrivateSectorInfo(ppt, sectors)
+	if err != nil {
+		return proof.PoStProof{}, nil, xerrors.Errorf("building private sector info: %w", err)
+	}
+
+	proofs, faulty, err := ffi.GenerateWindowPoSt(minerID, privSectors, randomness)
+	if err != nil {
+		return proof.PoStProof{}, faultySectorNumbers(faulty), xerrors.Errorf("ffi GenerateWindowPoSt: %w", err)
+	}
+	if len(proofs) != 1 {
+		return proof.PoStProof{}, faultySectorNumbers(faulty), xerrors.Errorf("expected 1 window PoSt proof, got %d", len(proofs))
+	}
+
+	return proofs[0], faultySectorNumbers(faulty), nil
+}
+
+// faultySectorNumbers strips the miner ID off the faulty-sector IDs
+// filecoin-ffi returns, matching the []abi.SectorNumber shape used by the
+// rest of lotus's window PoSt path instead of the raw []abi.SectorID.
+func faultySectorNumbers(faulty []abi.SectorID) []abi.SectorNumber {
+	if faulty == nil {
+		return nil
+	}
+
+	out := make([]abi.SectorNumber, len(faulty))
+	for i, id := range faulty {
+		out[i] = id.Number
+	}
+	return out
+}
+
+func ffiGenerateWinningPoSt(ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) ([]proof.PoStProof, error) {
+	privSectors, err := toFFIPrivateSectorInfo(ppt, sectors)
+	if err != nil {
+		return nil, xerrors.Errorf("building private sector info: %w", err)
+	}
+
+	proofs, err := ffi.GenerateWinningPoSt(minerID, privSectors, randomness)
+	if err != nil {
+		return nil, xerrors.Errorf("ffi GenerateWinningPoSt: %w", err)
+	}
+
+	return proofs, nil
+}
+
+// toFFIPrivateSectorInfo builds the FFI's view of each sector's on-disk
+// layout from the challenge descriptors the caller resolved against local
+// storage before invoking the Prover. PostSectorChallenge carries its own
+// cache/sealed paths precisely so Prover implementations don't need to
+// know anything about storiface.Store themselves.
+func toFFIPrivateSectorInfo(ppt abi.RegisteredPoStProof, sectors []storiface.PostSectorChallenge) (ffi.SortedPrivateSectorInfo, error) {
+	out := make([]ffi.PrivateSectorInfo, 0, len(sectors))
+	for _, s := range sectors {
+		out = append(out, ffi.PrivateSectorInfo{
+			SectorInfo: proof.SectorInfo{
+				SealProof:    s.SealProof,
+				SectorNumber: s.SectorNumber,
+				SealedCID:    s.SealedCID,
+			},
+			CacheDirPath:     s.CacheDirPath,
+			PoStProofType:    ppt,
+			SealedSectorPath: s.SealedSectorPath,
+		})
+	}
+
+	return ffi.NewSortedPrivateSectorInfo(out...), nil
+}
@@ -0,0 +1,53 @@
+package lpffi
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/proof"
+
+	"github.com/filecoin-project/lotus/storage/sealer/storiface"
+)
+
+// Prover abstracts the snark-heavy calls that SealCalls would otherwise make
+// directly against the in-process FFI bindings. Damocles-style deployments
+// want to run this compute on dedicated GPU boxes (or hand it to a
+// third-party proving service) instead of on the machine orchestrating the
+// sealing pipeline, so SealCalls is given a Prover at construction time and
+// never calls into the FFI directly itself.
+//
+// LocalProver is the default, in-process implementation used when no remote
+// prover is configured. ProverClient forwards the same calls over JSON-RPC
+// to a prover-server (see cmd/lotus-prover-server), so operators can scale
+// prover capacity independently of the rest of the curio pipeline.
+type Prover interface {
+	SealCommit2(ctx context.Context, sector storiface.SectorRef, phase1Out storiface.Commit1Out) (storiface.Proof, error)
+	ProveReplicaUpdate2(ctx context.Context, sector storiface.SectorRef, sectorKey, oldSealed, newSealed, newUnsealed cid.Cid, updateProofType abi.RegisteredUpdateProof) (storiface.Proof, error)
+	GenerateWindowPoSt(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) (proof.PoStProof, []abi.SectorNumber, error)
+	GenerateWinningPoSt(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) ([]proof.PoStProof, error)
+}
+
+// LocalProver runs all proving work in-process via the current FFI
+// bindings. It is the Prover SealCalls uses when no remote prover endpoint
+// is configured.
+type LocalProver struct{}
+
+var _ Prover = LocalProver{}
+
+func (LocalProver) SealCommit2(ctx context.Context, sector storiface.SectorRef, phase1Out storiface.Commit1Out) (storiface.Proof, error) {
+	return ffiSealCommit2(sector, phase1Out)
+}
+
+func (LocalProver) ProveReplicaUpdate2(ctx context.Context, sector storiface.SectorRef, sectorKey, oldSealed, newSealed, newUnsealed cid.Cid, updateProofType abi.RegisteredUpdateProof) (storiface.Proof, error) {
+	return ffiProveReplicaUpdate2(sector, sectorKey, oldSealed, newSealed, newUnsealed, updateProofType)
+}
+
+func (LocalProver) GenerateWindowPoSt(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) (proof.PoStProof, []abi.SectorNumber, error) {
+	return ffiGenerateWindowPoSt(ppt, minerID, sectors, randomness)
+}
+
+func (LocalProver) GenerateWinningPoSt(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) ([]proof.PoStProof, error) {
+	return ffiGenerateWinningPoSt(ppt, minerID, sectors, randomness)
+}
@@ -0,0 +1,96 @@
+package lpffi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/proof"
+
+	"github.com/filecoin-project/lotus/storage/sealer/storiface"
+)
+
+// proverRPC is the go-jsonrpc proxy shape used throughout lotus: a struct of
+// function fields that go-jsonrpc fills in via reflection when a client is
+// constructed, and that a server built on the same struct will dispatch to
+// an implementation of Prover.
+type proverRPC struct {
+	Internal struct {
+		SealCommit2         func(ctx context.Context, sector storiface.SectorRef, phase1Out storiface.Commit1Out) (storiface.Proof, error)
+		ProveReplicaUpdate2 func(ctx context.Context, sector storiface.SectorRef, sectorKey, oldSealed, newSealed, newUnsealed cid.Cid, updateProofType abi.RegisteredUpdateProof) (storiface.Proof, error)
+		GenerateWindowPoSt  func(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) (proof.PoStProof, []abi.SectorNumber, error)
+		GenerateWinningPoSt func(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) ([]proof.PoStProof, error)
+	}
+}
+
+// ProverClient forwards Prover calls to a remote prover-server (see
+// cmd/lotus-prover-server) over JSON-RPC. Each call gets its own deadline
+// derived from Timeout (zero means no deadline beyond the caller's
+// context), and cancelling the passed-in context aborts the in-flight
+// request the same way it would an in-process call. There is no streaming
+// progress or pubsub reporting; callers only see the final result or error.
+type ProverClient struct {
+	rpc proverRPC
+
+	closer jsonrpc.ClientCloser
+
+	// Timeout bounds each individual RPC call. Zero disables the
+	// per-request deadline.
+	Timeout time.Duration
+}
+
+var _ Prover = (*ProverClient)(nil)
+
+// NewProverClient dials a prover-server at addr (e.g.
+// "http://10.0.0.5:4701/rpc/v0") and returns a ProverClient backed by it;
+// its Close method must be called to release the underlying connection.
+func NewProverClient(ctx context.Context, addr string, requestHeader http.Header, timeout time.Duration) (*ProverClient, error) {
+	pc := &ProverClient{Timeout: timeout}
+
+	closer, err := jsonrpc.NewMergeClient(ctx, addr, "Prover", []interface{}{&pc.rpc.Internal}, requestHeader)
+	if err != nil {
+		return nil, err
+	}
+	pc.closer = closer
+
+	return pc, nil
+}
+
+func (c *ProverClient) Close() {
+	c.closer()
+}
+
+func (c *ProverClient) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.Timeout)
+}
+
+func (c *ProverClient) SealCommit2(ctx context.Context, sector storiface.SectorRef, phase1Out storiface.Commit1Out) (storiface.Proof, error) {
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
+	return c.rpc.Internal.SealCommit2(ctx, sector, phase1Out)
+}
+
+func (c *ProverClient) ProveReplicaUpdate2(ctx context.Context, sector storiface.SectorRef, sectorKey, oldSealed, newSealed, newUnsealed cid.Cid, updateProofType abi.RegisteredUpdateProof) (storiface.Proof, error) {
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
+	return c.rpc.Internal.ProveReplicaUpdate2(ctx, sector, sectorKey, oldSealed, newSealed, newUnsealed, updateProofType)
+}
+
+func (c *ProverClient) GenerateWindowPoSt(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) (proof.PoStProof, []abi.SectorNumber, error) {
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
+	return c.rpc.Internal.GenerateWindowPoSt(ctx, ppt, minerID, sectors, randomness)
+}
+
+func (c *ProverClient) GenerateWinningPoSt(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) ([]proof.PoStProof, error) {
+	ctx, cancel := c.withDeadline(ctx)
+	defer cancel()
+	return c.rpc.Internal.GenerateWinningPoSt(ctx, ppt, minerID, sectors, randomness)
+}
@@ -0,0 +1,120 @@
+package lpffi
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/proof"
+
+	"github.com/filecoin-project/lotus/storage/sealer/storiface"
+)
+
+// SealCalls is the seam between the curio task implementations (SDRTask,
+// the TreeD/TreeRC tasks, ...) and the actual sealing/proving work. All
+// snark work (SealCommit2, ProveReplicaUpdate2, the PoSt entrypoints) is
+// routed through the injected Prover so that it can be satisfied in-process
+// or by a remote prover-server without the tasks themselves needing to know
+// which. The snap-deals curiosrc/snap.ProveTask does not use it; it calls
+// curiosrc/ffi.SealCalls, which proves in-process.
+type SealCalls struct {
+	sectors SectorManager
+
+	prover Prover
+}
+
+// SectorManager is the narrow slice of storage/sealer functionality SealCalls
+// needs in order to stage sectors for sealing and to clean them up again.
+type SectorManager interface {
+	// AcquireSector resolves (allocating if necessary) local storage paths
+	// for sector, returning a release func that must be called once the
+	// caller is done with them.
+	AcquireSector(ctx context.Context, sector storiface.SectorRef, existing, allocate storiface.SectorFileType, pathType storiface.PathType) (storiface.SectorPaths, func(), error)
+
+	// Remove deletes all local sealed/cache/unsealed files for sector.
+	Remove(ctx context.Context, sector storiface.SectorRef) error
+}
+
+// NewSealCalls constructs a SealCalls backed by the given Prover. Passing
+// lpffi.LocalProver{} keeps proving in-process; passing a ProverClient
+// forwards it to a remote prover-server instead.
+func NewSealCalls(sectors SectorManager, prover Prover) *SealCalls {
+	if prover == nil {
+		prover = LocalProver{}
+	}
+
+	return &SealCalls{
+		sectors: sectors,
+		prover:  prover,
+	}
+}
+
+type StorageReservation struct {
+	release func()
+}
+
+func (r *StorageReservation) Release() {
+	if r != nil && r.release != nil {
+		r.release()
+	}
+}
+
+func (sc *SealCalls) ReserveSDRStorage(ctx context.Context, sector storiface.SectorRef) (*StorageReservation, error) {
+	// Storage reservation bookkeeping is unrelated to proving and is left
+	// as-is; this method pre-dates the Prover seam.
+	return &StorageReservation{release: func() {}}, nil
+}
+
+// GenerateSDR runs SDR replication for sector. Unlike SealCommit2 and
+// ProveReplicaUpdate2 it is CPU-bound tree building, not a snark, so it
+// does not go through the Prover seam - it acquires local storage directly
+// via SectorManager and calls into the FFI itself.
+func (sc *SealCalls) GenerateSDR(ctx context.Context, reservation *StorageReservation, sector storiface.SectorRef, ticket abi.SealRandomness, commd cid.Cid) error {
+	paths, releaseSector, err := sc.sectors.AcquireSector(ctx, sector, storiface.FTNone, storiface.FTCache|storiface.FTSealed, storiface.PathSealing)
+	if err != nil {
+		return xerrors.Errorf("acquiring sector storage paths: %w", err)
+	}
+	defer releaseSector()
+
+	if err := ffiGenerateSDR(sector, paths, ticket); err != nil {
+		return xerrors.Errorf("generating sdr: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseSectorFiles removes the sealed/cache/unsealed files for a sector
+// from local storage. Used once a sector is confirmed terminated on-chain
+// and its data no longer needs to be kept around.
+func (sc *SealCalls) ReleaseSectorFiles(ctx context.Context, sector storiface.SectorRef) error {
+	if err := sc.sectors.Remove(ctx, sector); err != nil {
+		return xerrors.Errorf("removing sector files: %w", err)
+	}
+	return nil
+}
+
+func (sc *SealCalls) SealCommit2(ctx context.Context, sector storiface.SectorRef, phase1Out storiface.Commit1Out) (storiface.Proof, error) {
+	out, err := sc.prover.SealCommit2(ctx, sector, phase1Out)
+	if err != nil {
+		return nil, xerrors.Errorf("prover SealCommit2: %w", err)
+	}
+	return out, nil
+}
+
+func (sc *SealCalls) ProveReplicaUpdate2(ctx context.Context, sector storiface.SectorRef, sectorKey, oldSealed, newSealed, newUnsealed cid.Cid, updateProofType abi.RegisteredUpdateProof) (storiface.Proof, error) {
+	out, err := sc.prover.ProveReplicaUpdate2(ctx, sector, sectorKey, oldSealed, newSealed, newUnsealed, updateProofType)
+	if err != nil {
+		return nil, xerrors.Errorf("prover ProveReplicaUpdate2: %w", err)
+	}
+	return out, nil
+}
+
+func (sc *SealCalls) GenerateWindowPoSt(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) (proof.PoStProof, []abi.SectorNumber, error) {
+	return sc.prover.GenerateWindowPoSt(ctx, ppt, minerID, sectors, randomness)
+}
+
+func (sc *SealCalls) GenerateWinningPoSt(ctx context.Context, ppt abi.RegisteredPoStProof, minerID abi.ActorID, sectors []storiface.PostSectorChallenge, randomness abi.PoStRandomness) ([]proof.PoStProof, error) {
+	return sc.prover.GenerateWinningPoSt(ctx, ppt, minerID, sectors, randomness)
+}
@@ -0,0 +1,53 @@
+package lpseal
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/lib/harmony/harmonydb"
+	"github.com/filecoin-project/lotus/lib/harmony/harmonytask"
+	"github.com/filecoin-project/lotus/lib/promise"
+)
+
+// TerminatePoller holds the AddTaskFunc promise TerminateTask registers via
+// Adder, and periodically assigns queued sectors_terminate rows to it so
+// CLI-enqueued terminations (see cmd/curio TerminateCmd) actually get
+// picked up instead of sitting in the table forever.
+type TerminatePoller struct {
+	db *harmonydb.DB
+
+	task promise.Promise[harmonytask.AddTaskFunc]
+}
+
+func NewTerminatePoller(db *harmonydb.DB) *TerminatePoller {
+	return &TerminatePoller{db: db}
+}
+
+// Poll looks for miners with sectors_terminate rows that aren't yet
+// assigned to a task and asks harmonytask to start a TerminateTask for
+// each one.
+func (tp *TerminatePoller) Poll(ctx context.Context) error {
+	addTask := tp.task.Val(ctx)
+
+	var spIDs []int64
+	err := tp.db.Select(ctx, &spIDs, `SELECT DISTINCT sp_id FROM sectors_terminate
+		WHERE task_id_terminate IS NULL AND after_terminate = false`)
+	if err != nil {
+		return xerrors.Errorf("listing miners with pending terminations: %w", err)
+	}
+
+	for _, spID := range spIDs {
+		spID := spID
+		addTask(func(id harmonytask.TaskID, tx *harmonydb.Tx) (bool, error) {
+			n, err := tx.Exec(`UPDATE sectors_terminate SET task_id_terminate = $1
+				WHERE sp_id = $2 AND task_id_terminate IS NULL AND after_terminate = false`, id, spID)
+			if err != nil {
+				return false, xerrors.Errorf("assigning terminate task: %w", err)
+			}
+			return n > 0, nil
+		})
+	}
+
+	return nil
+}
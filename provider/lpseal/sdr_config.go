@@ -0,0 +1,179 @@
+package lpseal
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/xerrors"
+)
+
+// SDRConfig controls how many cores an SDR task is allowed to use and how
+// it should be pinned to them. It is populated from the [Curio.SDR] config
+// table; LOTUS_HARMONY_SDR_THREADS overrides the configured thread count
+// for operators who want to tune it without touching the config file.
+type SDRConfig struct {
+	// Threads is the CPU count SDRTask reports to the harmony scheduler and,
+	// when greater than 1, switches on the multicore SDR builder. It is not
+	// passed to GenerateSDR and does not cap the threads the FFI spawns.
+	// Zero means "use the default single-core path".
+	Threads int
+
+	// SingleHasherPerThread disables rust-fil-proofs' multicore SDR tree
+	// builder (FIL_PROOFS_USE_MULTICORE_SDR), falling back to its single
+	// hasher path. Useful on boxes where Threads is pinned to 1 anyway, or
+	// when the multicore builder's memory footprint doesn't fit.
+	SingleHasherPerThread bool
+
+	// NUMANodeCPUs restricts the affinity mask to the logical CPUs of a
+	// single NUMA node, so the task doesn't get scheduled across sockets.
+	// Empty means "no NUMA restriction".
+	NUMANodeCPUs []int
+
+	// MaxRetries bounds how many times ReturnToSDR may send a sector back
+	// for a fresh SDR attempt before SDRTask gives up on it; see the
+	// task_retries column on sectors_sdr_pipeline.
+	MaxRetries int
+}
+
+const defaultSDRMaxRetries = 3
+
+// SDRConfigTable is the raw shape of the [Curio.SDR] config-file table.
+// DefaultSDRConfig turns it into an SDRConfig, resolving NUMANode into the
+// concrete CPU list SDRConfig.NUMANodeCPUs needs.
+type SDRConfigTable struct {
+	Threads               int
+	SingleHasherPerThread bool
+	NUMANode              *int
+	MaxRetries            int
+}
+
+// multicoreSDREnv is the environment variable rust-fil-proofs reads to pick
+// between its single-hasher-per-core and one-hasher-handles-all-layers SDR
+// implementations. DefaultSDRConfig sets it once per process, rather than
+// per-task, since it's read by the FFI at call time and setting it
+// concurrently from multiple SDR tasks would race.
+const multicoreSDREnv = "FIL_PROOFS_USE_MULTICORE_SDR"
+
+// DefaultSDRConfig returns the SDR tuning knobs, applying the
+// LOTUS_HARMONY_SDR_THREADS override on top of the config-file values.
+func DefaultSDRConfig(table SDRConfigTable) (SDRConfig, error) {
+	threads := table.Threads
+	if v, ok := os.LookupEnv("LOTUS_HARMONY_SDR_THREADS"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			threads = n
+		}
+	}
+
+	maxRetries := table.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultSDRMaxRetries
+	}
+
+	var cpus []int
+	if table.NUMANode != nil {
+		var err error
+		cpus, err = cpusForNUMANode(*table.NUMANode)
+		if err != nil {
+			return SDRConfig{}, xerrors.Errorf("resolving NUMA node %d cpu list: %w", *table.NUMANode, err)
+		}
+	}
+
+	multicore := !table.SingleHasherPerThread && threads > 1
+	if multicore {
+		if err := os.Setenv(multicoreSDREnv, "1"); err != nil {
+			return SDRConfig{}, xerrors.Errorf("setting %s: %w", multicoreSDREnv, err)
+		}
+	} else {
+		if err := os.Setenv(multicoreSDREnv, "0"); err != nil {
+			return SDRConfig{}, xerrors.Errorf("setting %s: %w", multicoreSDREnv, err)
+		}
+	}
+
+	return SDRConfig{
+		Threads:               threads,
+		SingleHasherPerThread: table.SingleHasherPerThread,
+		NUMANodeCPUs:          cpus,
+		MaxRetries:            maxRetries,
+	}, nil
+}
+
+// cpusForNUMANode reads the logical CPUs belonging to a NUMA node from
+// sysfs, e.g. "/sys/devices/system/node/node0/cpulist" containing "0-7,16-23".
+func cpusForNUMANode(node int) ([]int, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/sys/devices/system/node/node%d/cpulist", node))
+	if err != nil {
+		return nil, xerrors.Errorf("reading cpulist for NUMA node %d: %w", node, err)
+	}
+
+	return parseCPUList(strings.TrimSpace(string(data)))
+}
+
+// parseCPUList parses a Linux cpulist string ("0-3,8,10-11") into a slice
+// of individual CPU numbers.
+func parseCPUList(s string) ([]int, error) {
+	var out []int
+	if s == "" {
+		return out, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, xerrors.Errorf("parsing cpulist entry %q: %w", part, err)
+		}
+
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(hi)
+			if err != nil {
+				return nil, xerrors.Errorf("parsing cpulist entry %q: %w", part, err)
+			}
+		}
+
+		for cpu := start; cpu <= end; cpu++ {
+			out = append(out, cpu)
+		}
+	}
+
+	return out, nil
+}
+
+// PinToCPUSet locks the calling goroutine to its current OS thread and
+// restricts that thread's scheduling affinity to the CPUs in cpus. The
+// returned func must be called (typically via defer); it restores the
+// thread's previous affinity mask and releases the OS thread lock, since the
+// Go runtime reuses the thread for other goroutines afterwards.
+func PinToCPUSet(cpus []int) (func(), error) {
+	runtime.LockOSThread()
+
+	if len(cpus) == 0 {
+		return runtime.UnlockOSThread, nil
+	}
+
+	var orig unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &orig); err != nil {
+		runtime.UnlockOSThread()
+		return func() {}, xerrors.Errorf("sched_getaffinity: %w", err)
+	}
+
+	var set unix.CPUSet
+	set.Zero()
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		runtime.UnlockOSThread()
+		return func() {}, xerrors.Errorf("sched_setaffinity: %w", err)
+	}
+
+	return func() {
+		_ = unix.SchedSetaffinity(0, &orig)
+		runtime.UnlockOSThread()
+	}, nil
+}
@@ -0,0 +1,28 @@
+package lpseal
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/lotus/lib/harmony/harmonydb"
+)
+
+// ReturnToSDR sends a sector back to the SDR stage after a failed attempt,
+// incrementing task_retries so SDRTask.Do can enforce SDRConfig.MaxRetries
+// instead of retrying the same sector forever. Called from SDRTask.Do
+// itself today; a future Trees consumer stage that fails to make progress
+// with what SDR produced should call this too.
+func ReturnToSDR(ctx context.Context, db *harmonydb.DB, spID, sectorNumber int64) error {
+	n, err := db.Exec(ctx, `UPDATE sectors_sdr_pipeline
+		SET task_id_sdr = NULL, after_sdr = false, task_retries = task_retries + 1
+		WHERE sp_id = $1 AND sector_number = $2`, spID, sectorNumber)
+	if err != nil {
+		return xerrors.Errorf("returning sector %d/%d to SDR: %w", spID, sectorNumber, err)
+	}
+	if n != 1 {
+		return xerrors.Errorf("returning sector %d/%d to SDR: expected to update 1 row, updated %d", spID, sectorNumber, n)
+	}
+
+	return nil
+}
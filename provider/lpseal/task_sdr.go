@@ -37,16 +37,21 @@ type SDRTask struct {
 
 	sc *lpffi.SealCalls
 
-	max int
+	max        int
+	cfg        SDRConfig
+	sectorSize abi.SectorSize
 }
 
-func NewSDRTask(api SDRAPI, db *harmonydb.DB, sp *SealPoller, sc *lpffi.SealCalls, maxSDR int) *SDRTask {
+func NewSDRTask(api SDRAPI, db *harmonydb.DB, sp *SealPoller, sc *lpffi.SealCalls, maxSDR int, cfg SDRConfig, sectorSize abi.SectorSize) *SDRTask {
 	return &SDRTask{
 		api: api,
 		db:  db,
 		sp:  sp,
 		sc:  sc,
 		max: maxSDR,
+		cfg: cfg,
+
+		sectorSize: sectorSize,
 	}
 }
 
@@ -81,10 +86,11 @@ func (s *SDRTask) Do(taskID harmonytask.TaskID, data harmonytask.AcceptData, sti
 		SpID         int64                   `db:"sp_id"`
 		SectorNumber int64                   `db:"sector_number"`
 		RegSealProof abi.RegisteredSealProof `db:"reg_seal_proof"`
+		TaskRetries  int                     `db:"task_retries"`
 	}
 
 	err = s.db.Select(ctx, &sectorParamsArr, `
-		SELECT sp_id, sector_number, reg_seal_proof
+		SELECT sp_id, sector_number, reg_seal_proof, task_retries
 		FROM sectors_sdr_pipeline
 		WHERE task_id_sdr = $1`, taskID)
 	if err != nil {
@@ -96,6 +102,11 @@ func (s *SDRTask) Do(taskID harmonytask.TaskID, data harmonytask.AcceptData, sti
 	}
 	sectorParams := sectorParamsArr[0]
 
+	if s.cfg.MaxRetries > 0 && sectorParams.TaskRetries >= s.cfg.MaxRetries {
+		return false, xerrors.Errorf("sector %d/%d exceeded max SDR retries (%d >= %d), giving up",
+			sectorParams.SpID, sectorParams.SectorNumber, sectorParams.TaskRetries, s.cfg.MaxRetries)
+	}
+
 	var pieces []struct {
 		PieceIndex int64  `db:"piece_index"`
 		PieceCID   string `db:"piece_cid"`
@@ -166,13 +177,26 @@ func (s *SDRTask) Do(taskID harmonytask.TaskID, data harmonytask.AcceptData, sti
 	// FAIL-RESP: rely on harmony retry
 
 	// LATEFAIL: compute error in sdr
-	// LATEFAIL-RESP: Check in Trees task should catch this; Will retry computing
-	//                Trees; After one retry, it should return the sector to the
-	// 			      SDR stage; max number of retries should be configurable
+	// LATEFAIL-RESP: ReturnToSDR nulls task_id_sdr and bumps task_retries,
+	//                so the poller re-queues the sector for a fresh SDR
+	//                attempt; once task_retries reaches cfg.MaxRetries, the
+	//                MaxRetries check above fails the sector instead of
+	//                retrying it again. (There's no separate Trees
+	//                consumer stage in this pipeline yet - a failed
+	//                GenerateSDR is itself what drives the retry count.)
+
+	unpin, err := PinToCPUSet(s.cfg.NUMANodeCPUs)
+	if err != nil {
+		return false, xerrors.Errorf("pinning sdr worker thread: %w", err)
+	}
+	defer unpin()
 
 	err = s.sc.GenerateSDR(ctx, acceptData.reservation, sref, ticket, commd)
 	if err != nil {
-		return false, xerrors.Errorf("generating sdr: %w", err)
+		if rerr := ReturnToSDR(ctx, s.db, sectorParams.SpID, sectorParams.SectorNumber); rerr != nil {
+			return false, xerrors.Errorf("generating sdr: %w (and failed to return sector to SDR stage: %s)", err, rerr)
+		}
+		return true, nil
 	}
 
 	// store success!
@@ -257,13 +281,26 @@ func (s *SDRTask) CanAccept(ids []harmonytask.TaskID, engine *harmonytask.TaskEn
 }
 
 func (s *SDRTask) TypeDetails() harmonytask.TaskTypeDetails {
+	threads := s.cfg.Threads
+	if threads <= 0 {
+		threads = 1
+	}
+
+	// SDR's layer buffers are sized by the sector, not by thread count: each
+	// of the 11 layers the tree-builder streams is sectorSize bytes, plus a
+	// per-thread working-set allowance for whatever of that it keeps
+	// resident concurrently. Scaling purely off thread count (as a prior
+	// revision did) under-reserves RAM badly at low thread counts and
+	// invites OOM under the harmony scheduler.
+	const perThreadWorkingSet = 1 << 30
+
 	res := harmonytask.TaskTypeDetails{
 		Max:  s.max,
 		Name: "SDR",
 		Cost: resources.Resources{ // todo offset for prefetch?
-			Cpu: 4, // todo multicore sdr
+			Cpu: threads,
 			Gpu: 0,
-			Ram: 54 << 30,
+			Ram: uint64(s.sectorSize) + uint64(threads)*perThreadWorkingSet,
 		},
 		MaxFailures: 2,
 		Follows:     nil,
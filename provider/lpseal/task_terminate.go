@@ -0,0 +1,275 @@
+package lpseal
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	miner13 "github.com/filecoin-project/go-state-types/builtin/v13/miner"
+	"github.com/filecoin-project/go-state-types/dline"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/lib/harmony/harmonydb"
+	"github.com/filecoin-project/lotus/lib/harmony/harmonytask"
+	"github.com/filecoin-project/lotus/lib/harmony/resources"
+	"github.com/filecoin-project/lotus/provider/lpffi"
+	"github.com/filecoin-project/lotus/storage/sealer/storiface"
+)
+
+// TerminateAPI is the slice of chain API TerminateTask needs: enough to
+// locate a sector's deadline/partition, know which deadline is currently
+// open for proving, and push/await the TerminateSectors message.
+type TerminateAPI interface {
+	ChainHead(context.Context) (*types.TipSet, error)
+	StateSectorPartition(ctx context.Context, maddr address.Address, sectorNumber abi.SectorNumber, tsk types.TipSetKey) (*miner.SectorLocation, error)
+	StateMinerProvingDeadline(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*dline.Info, error)
+	StateMinerInfo(ctx context.Context, addr address.Address, tsk types.TipSetKey) (api.MinerInfo, error)
+	MpoolPushMessage(ctx context.Context, msg *types.Message, spec *api.MessageSendSpec) (*types.SignedMessage, error)
+	StateWaitMsg(ctx context.Context, cid cid.Cid, confidence uint64, limit abi.ChainEpoch, allowReplaced bool) (*api.MsgLookup, error)
+}
+
+// TerminateTask batches pending sector terminations (rows in
+// sectors_terminate) into miner.TerminateSectors messages, one message per
+// proving deadline so a single declaration never spans more than one
+// deadline. Sectors sitting in the deadline that is currently open (or
+// about to open) for proving are left for a later run so the termination
+// message can't race that deadline's WindowPoSt.
+type TerminateTask struct {
+	api    TerminateAPI
+	db     *harmonydb.DB
+	sc     *lpffi.SealCalls
+	poller *TerminatePoller
+
+	max int
+}
+
+func NewTerminateTask(api TerminateAPI, db *harmonydb.DB, sc *lpffi.SealCalls, poller *TerminatePoller, maxTerminate int) *TerminateTask {
+	return &TerminateTask{
+		api:    api,
+		db:     db,
+		sc:     sc,
+		poller: poller,
+		max:    maxTerminate,
+	}
+}
+
+func (t *TerminateTask) Do(taskID harmonytask.TaskID, stillOwned func() bool) (done bool, err error) {
+	ctx := context.Background()
+
+	var rows []struct {
+		SpID         int64 `db:"sp_id"`
+		SectorNumber int64 `db:"sector_number"`
+	}
+
+	err = t.db.Select(ctx, &rows, `
+		SELECT sp_id, sector_number
+		FROM sectors_terminate
+		WHERE task_id_terminate = $1 AND after_terminate = false`, taskID)
+	if err != nil {
+		return false, xerrors.Errorf("getting sectors to terminate: %w", err)
+	}
+	if len(rows) == 0 {
+		return false, xerrors.Errorf("no sectors assigned to terminate task %d", taskID)
+	}
+
+	spID := rows[0].SpID
+	maddr, err := address.NewIDAddress(uint64(spID))
+	if err != nil {
+		return false, xerrors.Errorf("getting miner address: %w", err)
+	}
+
+	ts, err := t.api.ChainHead(ctx)
+	if err != nil {
+		return false, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	// FAIL: chain API may be down while locating sectors
+	// FAIL-RESP: rely on harmony retry
+	openDeadline, err := t.api.StateMinerProvingDeadline(ctx, maddr, ts.Key())
+	if err != nil {
+		return false, xerrors.Errorf("getting proving deadline: %w", err)
+	}
+
+	type dpKey struct {
+		deadline  uint64
+		partition uint64
+	}
+
+	bySectors := map[dpKey][]uint64{}
+
+	for _, r := range rows {
+		loc, err := t.api.StateSectorPartition(ctx, maddr, abi.SectorNumber(r.SectorNumber), ts.Key())
+		if err != nil {
+			return false, xerrors.Errorf("locating sector %d: %w", r.SectorNumber, err)
+		}
+
+		k := dpKey{deadline: uint64(loc.Deadline), partition: uint64(loc.Partition)}
+		bySectors[k] = append(bySectors[k], uint64(r.SectorNumber))
+	}
+
+	// Respect the PreCommit/WindowPoSt deadline windows: don't terminate out
+	// of a deadline that is currently open (or the very next one) - those
+	// sectors are left in the table and picked up by a later task once the
+	// deadline has safely closed.
+	var batchDeadline uint64
+	haveBatchDeadline := false
+	var declarations []miner13.TerminationDeclaration
+	var covered []uint64
+
+	for k, sectorNums := range bySectors {
+		if k.deadline == uint64(openDeadline.Index) || k.deadline == (uint64(openDeadline.Index)+1)%uint64(openDeadline.WPoStPeriodDeadlines) {
+			continue
+		}
+
+		if haveBatchDeadline && k.deadline != batchDeadline {
+			// at most one deadline per message - further deadlines wait for
+			// a future task
+			continue
+		}
+		batchDeadline = k.deadline
+		haveBatchDeadline = true
+
+		bf := bitfield.NewFromSet(sectorNums)
+		declarations = append(declarations, miner13.TerminationDeclaration{
+			Deadline:  k.deadline,
+			Partition: k.partition,
+			Sectors:   bf,
+		})
+		covered = append(covered, sectorNums...)
+	}
+
+	// Poll claims every pending row for this miner onto taskID, but a single
+	// task only ever declares one deadline's worth of terminations. Release
+	// every row this task didn't cover - whether because it's in a deadline
+	// we're deliberately avoiding, or because it lost the "one deadline per
+	// message" cap above - so the poller re-queues it onto a future task
+	// instead of it being stranded with task_id_terminate stuck non-NULL.
+	coveredSet := make(map[uint64]bool, len(covered))
+	for _, sn := range covered {
+		coveredSet[sn] = true
+	}
+	var uncovered []uint64
+	for _, r := range rows {
+		if sn := uint64(r.SectorNumber); !coveredSet[sn] {
+			uncovered = append(uncovered, sn)
+		}
+	}
+	if len(uncovered) > 0 {
+		if _, err := t.db.Exec(ctx, `UPDATE sectors_terminate SET task_id_terminate = NULL
+			WHERE sp_id = $1 AND sector_number = ANY($2) AND task_id_terminate = $3`,
+			spID, uncovered, taskID); err != nil {
+			return false, xerrors.Errorf("releasing uncovered sectors back to the queue: %w", err)
+		}
+	}
+
+	if len(declarations) == 0 {
+		// Everything pending is in a deadline we're avoiding right now;
+		// nothing to do this round, try again later.
+		return true, nil
+	}
+
+	mi, err := t.api.StateMinerInfo(ctx, maddr, ts.Key())
+	if err != nil {
+		return false, xerrors.Errorf("getting miner info: %w", err)
+	}
+
+	params := miner13.TerminateSectorsParams{Terminations: declarations}
+	enc, aerr := actors.SerializeParams(&params)
+	if aerr != nil {
+		return false, xerrors.Errorf("serializing termination params: %w", aerr)
+	}
+
+	msg := &types.Message{
+		To:     maddr,
+		From:   mi.Worker,
+		Value:  big.Zero(),
+		Method: builtin.MethodsMiner.TerminateSectors,
+		Params: enc,
+	}
+
+	smsg, err := t.api.MpoolPushMessage(ctx, msg, nil)
+	if err != nil {
+		return false, xerrors.Errorf("pushing terminate sectors message: %w", err)
+	}
+
+	n, err := t.db.Exec(ctx, `UPDATE sectors_terminate SET terminate_cid = $1, request_epoch = $2
+		WHERE sp_id = $3 AND sector_number = ANY($4)`,
+		smsg.Cid(), ts.Height(), spID, covered)
+	if err != nil {
+		return false, xerrors.Errorf("recording terminate message cid: %w", err)
+	}
+	if n != len(covered) {
+		return false, xerrors.Errorf("expected to update %d rows, updated %d", len(covered), n)
+	}
+
+	// LATEFAIL: message lands but is rejected on-chain
+	// LATEFAIL-RESP: surfaced to the caller as an error; harmony retries the
+	//                task, which will push a fresh message for whatever in
+	//                this batch is still un-terminated.
+	rec, err := t.api.StateWaitMsg(ctx, smsg.Cid(), build.MessageConfidence, build.Finality, true)
+	if err != nil {
+		return false, xerrors.Errorf("waiting for terminate sectors message: %w", err)
+	}
+	if rec.Receipt.ExitCode != 0 {
+		return false, xerrors.Errorf("terminate sectors message %s failed with exit code %d", smsg.Cid(), rec.Receipt.ExitCode)
+	}
+
+	for _, sn := range covered {
+		sref := storiface.SectorRef{
+			ID: abi.SectorID{
+				Miner:  abi.ActorID(spID),
+				Number: abi.SectorNumber(sn),
+			},
+		}
+
+		if err := t.sc.ReleaseSectorFiles(ctx, sref); err != nil {
+			log.Errorw("releasing terminated sector files", "sp", spID, "sector", sn, "error", err)
+		}
+	}
+
+	n, err = t.db.Exec(ctx, `UPDATE sectors_terminate SET after_terminate = true
+		WHERE sp_id = $1 AND sector_number = ANY($2)`, spID, covered)
+	if err != nil {
+		return false, xerrors.Errorf("marking sectors terminated: %w", err)
+	}
+	if n != len(covered) {
+		return false, xerrors.Errorf("expected to mark %d rows terminated, marked %d", len(covered), n)
+	}
+
+	return true, nil
+}
+
+func (t *TerminateTask) CanAccept(ids []harmonytask.TaskID, engine *harmonytask.TaskEngine) (*harmonytask.TaskID, error) {
+	id := ids[0]
+	return &id, nil
+}
+
+func (t *TerminateTask) TypeDetails() harmonytask.TaskTypeDetails {
+	return harmonytask.TaskTypeDetails{
+		Max:  t.max,
+		Name: "TerminateSectors",
+		Cost: resources.Resources{
+			Cpu: 1,
+			Gpu: 0,
+			Ram: 128 << 20,
+		},
+		MaxFailures: 10,
+		IAmBored:    nil,
+	}
+}
+
+func (t *TerminateTask) Adder(taskFunc harmonytask.AddTaskFunc) {
+	t.poller.task.Set(taskFunc)
+}
+
+var _ harmonytask.TaskInterface = &TerminateTask{}